@@ -0,0 +1,257 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/iam"
+	"cloud.google.com/go/pubsub"
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the root of a -config file, a structured alternative to the
+// PUBSUB_PROJECT{N} environment variables.
+type Config struct {
+	Projects []ProjectConfig `yaml:"projects" json:"projects"`
+}
+
+// ProjectConfig describes everything pubsubc should provision within a
+// single GCP project.
+type ProjectConfig struct {
+	ID      string         `yaml:"id" json:"id"`
+	Schemas []SchemaConfig `yaml:"schemas,omitempty" json:"schemas,omitempty"`
+	Topics  []TopicConfig  `yaml:"topics" json:"topics"`
+}
+
+// TopicConfig describes a topic, its subscriptions and its IAM bindings.
+type TopicConfig struct {
+	ID             string               `yaml:"id" json:"id"`
+	Schema         string               `yaml:"schema,omitempty" json:"schema,omitempty"`
+	SchemaEncoding string               `yaml:"schemaEncoding,omitempty" json:"schemaEncoding,omitempty"`
+	IAMBindings    []IAMBinding         `yaml:"iamBindings,omitempty" json:"iamBindings,omitempty"`
+	Subscriptions  []SubscriptionConfig `yaml:"subscriptions" json:"subscriptions"`
+
+	// Seed is a glob pattern for fixture message files published to the
+	// topic right after it is created; see SeedMessage for the file format.
+	Seed string `yaml:"seed,omitempty" json:"seed,omitempty"`
+}
+
+// SubscriptionConfig mirrors SubscriptionSpec, but as it's read from a
+// -config file rather than parsed from the env-var DSL. Durations are plain
+// strings in the same format accepted by time.ParseDuration (e.g. "30s").
+type SubscriptionConfig struct {
+	ID                     string       `yaml:"id" json:"id"`
+	PushEndpoint           string       `yaml:"pushEndpoint,omitempty" json:"pushEndpoint,omitempty"`
+	PushAuthServiceAccount string       `yaml:"pushAuthServiceAccount,omitempty" json:"pushAuthServiceAccount,omitempty"`
+	AckDeadline            string       `yaml:"ackDeadline,omitempty" json:"ackDeadline,omitempty"`
+	RetentionDuration      string       `yaml:"retentionDuration,omitempty" json:"retentionDuration,omitempty"`
+	DeadLetterTopic        string       `yaml:"deadLetterTopic,omitempty" json:"deadLetterTopic,omitempty"`
+	MaxDeliveryAttempts    int          `yaml:"maxDeliveryAttempts,omitempty" json:"maxDeliveryAttempts,omitempty"`
+	EnableOrdering         bool         `yaml:"enableOrdering,omitempty" json:"enableOrdering,omitempty"`
+	EnableExactlyOnce      bool         `yaml:"enableExactlyOnce,omitempty" json:"enableExactlyOnce,omitempty"`
+	Filter                 string       `yaml:"filter,omitempty" json:"filter,omitempty"`
+	MinBackoff             string       `yaml:"minBackoff,omitempty" json:"minBackoff,omitempty"`
+	MaxBackoff             string       `yaml:"maxBackoff,omitempty" json:"maxBackoff,omitempty"`
+	IAMBindings            []IAMBinding `yaml:"iamBindings,omitempty" json:"iamBindings,omitempty"`
+}
+
+// IAMBinding grants role to members on the topic or subscription it is
+// declared under.
+type IAMBinding struct {
+	Role    string   `yaml:"role" json:"role"`
+	Members []string `yaml:"members" json:"members"`
+}
+
+// SchemaConfig declares a schema to provision via pubsub.SchemaClient before
+// any topic that references it is created.
+type SchemaConfig struct {
+	ID             string `yaml:"id" json:"id"`
+	Type           string `yaml:"type" json:"type"`
+	DefinitionFile string `yaml:"definitionFile,omitempty" json:"definitionFile,omitempty"`
+	Definition     string `yaml:"definition,omitempty" json:"definition,omitempty"`
+}
+
+// provisionSchemas creates every declared schema, resolving its definition
+// from either an inline string or a file path.
+func provisionSchemas(ctx context.Context, projectID string, schemas []SchemaConfig) error {
+	for _, schemaCfg := range schemas {
+		definition := schemaCfg.Definition
+		if schemaCfg.DefinitionFile != "" {
+			data, err := os.ReadFile(schemaCfg.DefinitionFile)
+			if err != nil {
+				return fmt.Errorf("Unable to read schema definition file %q for schema %q: %s", schemaCfg.DefinitionFile, schemaCfg.ID, err)
+			}
+			definition = string(data)
+		}
+
+		if err := createSchema(ctx, projectID, schemaCfg.ID, schemaCfg.Type, definition); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadConfig reads and parses a -config file. The file format (YAML or JSON)
+// is inferred from its extension.
+func loadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read config file %q: %s", path, err)
+	}
+
+	var cfg Config
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		if err := yaml.Unmarshal(data, &cfg); err != nil {
+			return nil, fmt.Errorf("Unable to parse YAML config file %q: %s", path, err)
+		}
+	case ".json":
+		if err := json.Unmarshal(data, &cfg); err != nil {
+			return nil, fmt.Errorf("Unable to parse JSON config file %q: %s", path, err)
+		}
+	default:
+		return nil, fmt.Errorf("Unsupported config file extension %q: expected .yaml, .yml or .json", ext)
+	}
+
+	return &cfg, nil
+}
+
+// toSpec converts a SubscriptionConfig into the SubscriptionSpec consumed by
+// createOrUpdateSubscription, parsing its duration fields along the way.
+func (s SubscriptionConfig) toSpec() (SubscriptionSpec, error) {
+	spec := SubscriptionSpec{
+		ID:                     s.ID,
+		DeadLetterTopic:        s.DeadLetterTopic,
+		MaxDeliveryAttempts:    s.MaxDeliveryAttempts,
+		EnableOrdering:         s.EnableOrdering,
+		EnableExactlyOnce:      s.EnableExactlyOnce,
+		Filter:                 s.Filter,
+		PushAuthServiceAccount: s.PushAuthServiceAccount,
+	}
+
+	var err error
+	if spec.AckDeadline, err = parseOptionalDuration(s.AckDeadline); err != nil {
+		return spec, fmt.Errorf("invalid ackDeadline %q for subscription %q: %s", s.AckDeadline, s.ID, err)
+	}
+	if spec.RetentionDuration, err = parseOptionalDuration(s.RetentionDuration); err != nil {
+		return spec, fmt.Errorf("invalid retentionDuration %q for subscription %q: %s", s.RetentionDuration, s.ID, err)
+	}
+	if spec.MinBackoff, err = parseOptionalDuration(s.MinBackoff); err != nil {
+		return spec, fmt.Errorf("invalid minBackoff %q for subscription %q: %s", s.MinBackoff, s.ID, err)
+	}
+	if spec.MaxBackoff, err = parseOptionalDuration(s.MaxBackoff); err != nil {
+		return spec, fmt.Errorf("invalid maxBackoff %q for subscription %q: %s", s.MaxBackoff, s.ID, err)
+	}
+
+	return spec, nil
+}
+
+func parseOptionalDuration(value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+	return time.ParseDuration(value)
+}
+
+// createFromConfig provisions a single project declared in a -config file,
+// reusing the same createOrGetTopic/createOrUpdateSubscription/pruneUndeclared
+// helpers that back the env-var DSL, plus the IAM bindings the DSL has no
+// syntax for.
+func createFromConfig(ctx context.Context, project ProjectConfig) error {
+	client, err := pubsub.NewClient(ctx, project.ID)
+	if err != nil {
+		return fmt.Errorf("Unable to create client to project %q: %s", project.ID, err)
+	}
+	defer client.Close()
+
+	debugf("Client connected with project ID %q", project.ID)
+
+	if err := provisionSchemas(ctx, project.ID, project.Schemas); err != nil {
+		return err
+	}
+
+	declaredSubs := make(map[string]bool)
+	declaredTopics := make(map[string]bool)
+
+	for _, topicCfg := range project.Topics {
+		declaredTopics[topicCfg.ID] = true
+
+		topicSpec := TopicSpec{ID: topicCfg.ID, Schema: topicCfg.Schema, Encoding: topicCfg.SchemaEncoding, Seed: topicCfg.Seed}
+		topic, existed, err := createOrGetTopic(ctx, client, project.ID, topicSpec)
+		if err != nil {
+			return err
+		}
+
+		if err := applyIAMBindings(ctx, topic.IAM(), "topic", topicCfg.ID, topicCfg.IAMBindings); err != nil {
+			return err
+		}
+
+		for _, subCfg := range topicCfg.Subscriptions {
+			spec, err := subCfg.toSpec()
+			if err != nil {
+				return err
+			}
+			declaredSubs[spec.ID] = true
+			if spec.DeadLetterTopic != "" {
+				declaredTopics[spec.DeadLetterTopic] = true
+			}
+
+			if err := createOrUpdateSubscription(ctx, client, topic, project.ID, topicCfg.ID, spec, subCfg.PushEndpoint); err != nil {
+				return err
+			}
+			if err := applyIAMBindings(ctx, client.Subscription(spec.ID).IAM(), "subscription", spec.ID, subCfg.IAMBindings); err != nil {
+				return err
+			}
+		}
+
+		// Seeding runs after subscriptions are provisioned: a subscription
+		// only receives messages published after its own creation, so
+		// publishing any earlier would make the seed messages undeliverable.
+		if topicCfg.Seed != "" {
+			if existed {
+				debugf("  Skipping seed messages for topic %q: already existed", topicCfg.ID)
+			} else if err := seedTopic(ctx, topic, topicCfg.Seed); err != nil {
+				return err
+			}
+		}
+	}
+
+	if *prune {
+		if err := pruneUndeclared(ctx, client, project.ID, declaredTopics, declaredSubs); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// applyIAMBindings grants each declared binding on the topic or subscription
+// identified by handle. It's a no-op when bindings is empty.
+func applyIAMBindings(ctx context.Context, handle *iam.Handle, resourceKind, resourceID string, bindings []IAMBinding) error {
+	if len(bindings) == 0 {
+		return nil
+	}
+
+	policy, err := handle.Policy(ctx)
+	if err != nil {
+		return fmt.Errorf("Unable to get IAM policy for %s %q: %s", resourceKind, resourceID, err)
+	}
+
+	for _, binding := range bindings {
+		for _, member := range binding.Members {
+			policy.Add(member, iam.RoleName(binding.Role))
+		}
+	}
+
+	if err := handle.SetPolicy(ctx, policy); err != nil {
+		return fmt.Errorf("Unable to set IAM policy for %s %q: %s", resourceKind, resourceID, err)
+	}
+
+	debugf("  Applied %d IAM binding(s) to %s %q", len(bindings), resourceKind, resourceID)
+	return nil
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoadConfig(t *testing.T) {
+	tests := []struct {
+		name     string
+		filename string
+		content  string
+		wantErr  bool
+	}{
+		{
+			"yaml",
+			"config.yaml",
+			"projects:\n  - id: proj1\n    topics:\n      - id: topic1\n        subscriptions:\n          - id: sub1\n",
+			false,
+		},
+		{
+			"json",
+			"config.json",
+			`{"projects":[{"id":"proj1","topics":[{"id":"topic1","subscriptions":[{"id":"sub1"}]}]}]}`,
+			false,
+		},
+		{"unsupported extension", "config.txt", "irrelevant", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.filename)
+			if err := os.WriteFile(path, []byte(tt.content), 0o644); err != nil {
+				t.Fatalf("WriteFile: %s", err)
+			}
+
+			cfg, err := loadConfig(path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("loadConfig(%q) error = %v, wantErr %v", tt.filename, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if len(cfg.Projects) != 1 || cfg.Projects[0].ID != "proj1" {
+				t.Errorf("loadConfig(%q) = %+v, want a single project %q", tt.filename, cfg, "proj1")
+			}
+		})
+	}
+
+	if _, err := loadConfig(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Error("loadConfig of a missing file: expected error, got nil")
+	}
+}
+
+func TestSubscriptionConfigToSpec(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      SubscriptionConfig
+		want    SubscriptionSpec
+		wantErr bool
+	}{
+		{
+			"minimal",
+			SubscriptionConfig{ID: "sub1"},
+			SubscriptionSpec{ID: "sub1"},
+			false,
+		},
+		{
+			"full",
+			SubscriptionConfig{
+				ID:                     "sub1",
+				PushAuthServiceAccount: "sa@project.iam.gserviceaccount.com",
+				AckDeadline:            "30s",
+				RetentionDuration:      "1h",
+				DeadLetterTopic:        "deadtopic",
+				MaxDeliveryAttempts:    5,
+				EnableOrdering:         true,
+				EnableExactlyOnce:      true,
+				Filter:                 `attributes.foo="bar"`,
+				MinBackoff:             "10s",
+				MaxBackoff:             "60s",
+			},
+			SubscriptionSpec{
+				ID:                     "sub1",
+				PushAuthServiceAccount: "sa@project.iam.gserviceaccount.com",
+				AckDeadline:            30 * time.Second,
+				RetentionDuration:      time.Hour,
+				DeadLetterTopic:        "deadtopic",
+				MaxDeliveryAttempts:    5,
+				EnableOrdering:         true,
+				EnableExactlyOnce:      true,
+				Filter:                 `attributes.foo="bar"`,
+				MinBackoff:             10 * time.Second,
+				MaxBackoff:             60 * time.Second,
+			},
+			false,
+		},
+		{"invalid ackDeadline", SubscriptionConfig{ID: "sub1", AckDeadline: "notaduration"}, SubscriptionSpec{}, true},
+		{"invalid retentionDuration", SubscriptionConfig{ID: "sub1", RetentionDuration: "notaduration"}, SubscriptionSpec{}, true},
+		{"invalid minBackoff", SubscriptionConfig{ID: "sub1", MinBackoff: "notaduration"}, SubscriptionSpec{}, true},
+		{"invalid maxBackoff", SubscriptionConfig{ID: "sub1", MaxBackoff: "notaduration"}, SubscriptionSpec{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.in.toSpec()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("toSpec() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("toSpec() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -2,20 +2,30 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"os"
+	"path/filepath"
 	"runtime"
+	"strconv"
 	"strings"
+	"time"
 
 	"cloud.google.com/go/pubsub"
+	"cloud.google.com/go/pubsublite"
 	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 var (
-	debug   = flag.Bool("debug", false, "Enable debug logging")
-	help    = flag.Bool("help", false, "Display usage information")
-	version = flag.Bool("version", false, "Display version information")
+	debug     = flag.Bool("debug", false, "Enable debug logging")
+	help      = flag.Bool("help", false, "Display usage information")
+	version   = flag.Bool("version", false, "Display version information")
+	reconcile = flag.Bool("reconcile", false, "Reuse and update topics/subscriptions that already exist instead of failing")
+	prune     = flag.Bool("prune", false, "Delete topics and subscriptions not declared in the environment variables or -config file (requires -reconcile)")
+	config    = flag.String("config", "", "Path to a YAML/JSON config file declaring projects, topics and subscriptions instead of using PUBSUB_PROJECT{N}")
 )
 
 // The CommitHash and Revision variables are set during building.
@@ -44,6 +54,11 @@ func fatalf(format string, params ...interface{}) {
 	os.Exit(1)
 }
 
+// warnf prints a non-fatal warning to stderr, regardless of -debug.
+func warnf(format string, params ...interface{}) {
+	fmt.Fprintf(os.Stderr, os.Args[0]+": warning: "+format+"\n", params...)
+}
+
 func displayResult(projectId string) {
 	ctx := context.Background()
 	client, err := pubsub.NewClient(ctx, projectId)
@@ -116,7 +131,9 @@ func listTopics(client *pubsub.Client) ([]*pubsub.Topic, error) {
 }
 
 // create a connection to the PubSub service and create topics and subscriptions
-// for the specified project ID.
+// for the specified project ID. When -reconcile is set, topics and subscriptions
+// that already exist are reused (and their push endpoint updated if it drifted)
+// instead of failing with AlreadyExists.
 func create(ctx context.Context, projectID string, topics Topics) error {
 	client, err := pubsub.NewClient(ctx, projectID)
 	if err != nil {
@@ -126,40 +143,697 @@ func create(ctx context.Context, projectID string, topics Topics) error {
 
 	debugf("Client connected with project ID %q", projectID)
 
-	for topicID, subscriptions := range topics {
-		debugf("  Creating topic %q", topicID)
-		topic, err := client.CreateTopic(ctx, topicID)
+	declaredSubs := make(map[string]bool)
+	declaredTopics := make(map[string]bool)
+
+	for topicSpecRaw, subscriptions := range topics {
+		topicSpec, err := parseTopicSpec(topicSpecRaw)
+		if err != nil {
+			return err
+		}
+		declaredTopics[topicSpec.ID] = true
+
+		topic, existed, err := createOrGetTopic(ctx, client, projectID, topicSpec)
 		if err != nil {
-			return fmt.Errorf("Unable to create topic %q for project %q: %s", topicID, projectID, err)
+			return err
 		}
 
 		for _, subscription := range subscriptions {
 			subscriptionParts := strings.Split(subscription, "+")
-			subscriptionID := strings.TrimSpace(subscriptionParts[0])
+
+			spec, err := parseSubscriptionSpec(strings.TrimSpace(subscriptionParts[0]))
+			if err != nil {
+				return err
+			}
+			declaredSubs[spec.ID] = true
+			if spec.DeadLetterTopic != "" {
+				declaredTopics[spec.DeadLetterTopic] = true
+			}
+
 			var pushEndpoint string
 			if len(subscriptionParts) > 1 {
 				pushEndpoint = strings.Replace(strings.TrimSpace(subscriptionParts[1]), "|", ":", 1)
 			} else {
 				pushEndpoint = ""
 			}
+
+			var endPointURL string
 			if pushEndpoint != "" {
-				endPointURL := "http://" + pushEndpoint
-				debugf("    Creating subscription %q - endpoint %q", subscriptionID, endPointURL)
-				pushConfig := pubsub.PushConfig{Endpoint: endPointURL}
-				_, err = client.CreateSubscription(
-					ctx,
-					subscriptionID,
-					pubsub.SubscriptionConfig{Topic: topic, PushConfig: pushConfig},
-				)
+				endPointURL = "http://" + pushEndpoint
+			}
+
+			if err := createOrUpdateSubscription(ctx, client, topic, projectID, topicSpec.ID, spec, endPointURL); err != nil {
+				return err
+			}
+		}
+
+		// Seeding runs after subscriptions are provisioned: a subscription
+		// only receives messages published after its own creation, so
+		// publishing any earlier would make the seed messages undeliverable.
+		if topicSpec.Seed != "" {
+			if existed {
+				debugf("  Skipping seed messages for topic %q: already existed", topicSpec.ID)
+			} else if err := seedTopic(ctx, topic, topicSpec.Seed); err != nil {
+				return err
+			}
+		}
+	}
+
+	if *prune {
+		if err := pruneUndeclared(ctx, client, projectID, declaredTopics, declaredSubs); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// TopicSpec describes a regular Pub/Sub topic's provisioning options, parsed
+// from the "topicID" or "topicID{schema=my-schema,encoding=JSON,seed=./fixtures/*.json}"
+// syntax. Seed is a glob pattern for fixture message files published to the
+// topic right after it is created.
+type TopicSpec struct {
+	ID       string
+	Schema   string
+	Encoding string
+	Seed     string
+}
+
+// parseTopicSpec parses a "topicID" or "topicID{schema=my-schema,encoding=JSON,seed=...}"
+// token from the env-var DSL into a TopicSpec.
+func parseTopicSpec(raw string) (TopicSpec, error) {
+	spec := TopicSpec{}
+
+	id := raw
+	if idx := strings.Index(raw, "{"); idx != -1 {
+		if !strings.HasSuffix(raw, "}") {
+			return spec, fmt.Errorf("malformed topic options %q: missing closing '}'", raw)
+		}
+		id = raw[:idx]
+
+		for _, option := range strings.Split(raw[idx+1:len(raw)-1], ",") {
+			kv := strings.SplitN(option, "=", 2)
+			if len(kv) != 2 {
+				return spec, fmt.Errorf("malformed topic option %q in %q", option, raw)
+			}
+			key, value := strings.TrimSpace(kv[0]), strings.TrimSpace(kv[1])
+
+			switch key {
+			case "schema":
+				spec.Schema = value
+			case "encoding":
+				spec.Encoding = value
+			case "seed":
+				spec.Seed = value
+			default:
+				return spec, fmt.Errorf("unknown topic option %q in %q", key, raw)
+			}
+		}
+	}
+
+	spec.ID = strings.TrimSpace(id)
+	return spec, nil
+}
+
+// schemaEncoding maps the "encoding=JSON|BINARY" option to a pubsub.SchemaEncoding.
+func schemaEncoding(raw string) (pubsub.SchemaEncoding, error) {
+	switch strings.ToUpper(raw) {
+	case "", "JSON":
+		return pubsub.EncodingJSON, nil
+	case "BINARY":
+		return pubsub.EncodingBinary, nil
+	default:
+		return pubsub.EncodingUnspecified, fmt.Errorf("unknown schema encoding %q", raw)
+	}
+}
+
+// createOrGetTopic creates the topic described by spec, binding it to its
+// schema when one is declared, or, in -reconcile mode, returns the existing
+// topic handle if it is already provisioned. The returned bool reports
+// whether the topic already existed, so callers know not to re-run
+// one-time provisioning steps like seeding against it.
+func createOrGetTopic(ctx context.Context, client *pubsub.Client, projectID string, spec TopicSpec) (*pubsub.Topic, bool, error) {
+	if *reconcile {
+		topic := client.Topic(spec.ID)
+		exists, err := topic.Exists(ctx)
+		if err != nil {
+			return nil, false, fmt.Errorf("Unable to check if topic %q exists for project %q: %s", spec.ID, projectID, err)
+		}
+		if exists {
+			debugf("  Topic %q already exists, reusing it", spec.ID)
+			return topic, true, nil
+		}
+	}
+
+	if spec.Schema == "" {
+		debugf("  Creating topic %q", spec.ID)
+		topic, err := client.CreateTopic(ctx, spec.ID)
+		if err != nil {
+			return nil, false, fmt.Errorf("Unable to create topic %q for project %q: %s", spec.ID, projectID, err)
+		}
+		return topic, false, nil
+	}
+
+	encoding, err := schemaEncoding(spec.Encoding)
+	if err != nil {
+		return nil, false, fmt.Errorf("Unable to create topic %q for project %q: %s", spec.ID, projectID, err)
+	}
+
+	debugf("  Creating topic %q with schema %q", spec.ID, spec.Schema)
+	topic, err := client.CreateTopicWithConfig(ctx, spec.ID, &pubsub.TopicConfig{
+		SchemaSettings: &pubsub.SchemaSettings{
+			Schema:   fmt.Sprintf("projects/%s/schemas/%s", projectID, spec.Schema),
+			Encoding: encoding,
+		},
+	})
+	if err != nil {
+		return nil, false, fmt.Errorf("Unable to create topic %q with schema %q for project %q: %s", spec.ID, spec.Schema, projectID, err)
+	}
+	return topic, false, nil
+}
+
+// createSchema provisions a single schema in the Pub/Sub schema registry, or,
+// in -reconcile mode, leaves it alone if it's already registered (schemas are
+// immutable in the Pub/Sub API, so there's nothing to update).
+func createSchema(ctx context.Context, projectID, schemaID, schemaType, definition string) error {
+	client, err := pubsub.NewSchemaClient(ctx, projectID)
+	if err != nil {
+		return fmt.Errorf("Unable to create schema client for project %q: %s", projectID, err)
+	}
+	defer client.Close()
+
+	var t pubsub.SchemaType
+	switch strings.ToUpper(schemaType) {
+	case "AVRO":
+		t = pubsub.SchemaAvro
+	case "PROTOCOL_BUFFER":
+		t = pubsub.SchemaProtocolBuffer
+	default:
+		return fmt.Errorf("Unknown schema type %q for schema %q", schemaType, schemaID)
+	}
+
+	if *reconcile {
+		_, err := client.Schema(ctx, schemaID, pubsub.SchemaViewBasic)
+		if err == nil {
+			debugf("  Schema %q already exists, reusing it", schemaID)
+			return nil
+		}
+		if status.Code(err) != codes.NotFound {
+			return fmt.Errorf("Unable to check if schema %q exists for project %q: %s", schemaID, projectID, err)
+		}
+	}
+
+	debugf("  Creating schema %q (%s)", schemaID, schemaType)
+	_, err = client.CreateSchema(ctx, schemaID, pubsub.SchemaConfig{Type: t, Definition: definition})
+	if err != nil {
+		return fmt.Errorf("Unable to create schema %q for project %q: %s", schemaID, projectID, err)
+	}
+	return nil
+}
+
+// SeedMessage is the on-disk JSON shape of a fixture message referenced by a
+// topic's seed option. Its contents are published to the topic right after
+// the topic is created.
+type SeedMessage struct {
+	Data        string            `json:"data"`
+	Attributes  map[string]string `json:"attributes,omitempty"`
+	OrderingKey string            `json:"orderingKey,omitempty"`
+}
+
+// seedTopic publishes every fixture message matching pattern to topic,
+// waiting for all of them to be acknowledged before returning.
+func seedTopic(ctx context.Context, topic *pubsub.Topic, pattern string) error {
+	paths, err := filepath.Glob(pattern)
+	if err != nil {
+		return fmt.Errorf("Unable to expand seed message pattern %q for topic %q: %s", pattern, topic.ID(), err)
+	}
+	if len(paths) == 0 {
+		warnf("seed pattern %q for topic %q matched no files", pattern, topic.ID())
+		return nil
+	}
+
+	messages := make([]SeedMessage, 0, len(paths))
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("Unable to read seed message file %q for topic %q: %s", path, topic.ID(), err)
+		}
+
+		var msg SeedMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			return fmt.Errorf("Unable to parse seed message file %q for topic %q: %s", path, topic.ID(), err)
+		}
+		if msg.OrderingKey != "" {
+			topic.EnableMessageOrdering = true
+		}
+		messages = append(messages, msg)
+	}
+
+	results := make([]*pubsub.PublishResult, 0, len(paths))
+	for i, msg := range messages {
+		debugf("  Publishing seed message %q to topic %q", paths[i], topic.ID())
+		results = append(results, topic.Publish(ctx, &pubsub.Message{
+			Data:        []byte(msg.Data),
+			Attributes:  msg.Attributes,
+			OrderingKey: msg.OrderingKey,
+		}))
+	}
+
+	for i, result := range results {
+		if _, err := result.Get(ctx); err != nil {
+			return fmt.Errorf("Unable to publish seed message %q to topic %q: %s", paths[i], topic.ID(), err)
+		}
+	}
+
+	return nil
+}
+
+// SubscriptionSpec describes a subscription's full configuration, parsed from
+// the "sub[ack=30s,dlq=deadtopic:5,filter=\"...\",ordering,exactly_once]" syntax.
+type SubscriptionSpec struct {
+	ID                  string
+	AckDeadline         time.Duration
+	RetentionDuration   time.Duration
+	DeadLetterTopic     string
+	MaxDeliveryAttempts int
+	EnableOrdering      bool
+	EnableExactlyOnce   bool
+	Filter              string
+	MinBackoff          time.Duration
+	MaxBackoff          time.Duration
+
+	// PushAuthServiceAccount is only populated when the subscription is
+	// declared via a -config file; the env-var DSL has no syntax for it.
+	PushAuthServiceAccount string
+}
+
+// parseSubscriptionSpec parses a "subID" or "subID[ack=30s,dlq=deadtopic:5,...]"
+// token from the env-var DSL into a SubscriptionSpec.
+func parseSubscriptionSpec(raw string) (SubscriptionSpec, error) {
+	spec := SubscriptionSpec{}
+
+	id := raw
+	if idx := strings.Index(raw, "["); idx != -1 {
+		if !strings.HasSuffix(raw, "]") {
+			return spec, fmt.Errorf("malformed subscription options %q: missing closing ']'", raw)
+		}
+		id = raw[:idx]
+
+		for _, option := range splitRespectingDelimiters(raw[idx+1:len(raw)-1], ',') {
+			kv := strings.SplitN(option, "=", 2)
+			key := strings.TrimSpace(kv[0])
+			var value string
+			if len(kv) == 2 {
+				value = unescapeQuoted(strings.TrimSpace(kv[1]))
+			}
+
+			switch key {
+			case "ack":
+				d, err := time.ParseDuration(value)
 				if err != nil {
-					return fmt.Errorf("Unable to create push subscription %q on topic %q for project %q using push endpoint %q: %s", subscriptionID, topicID, projectID, pushEndpoint, err)
+					return spec, fmt.Errorf("invalid ack value %q in %q: %s", value, raw, err)
 				}
-			} else {
-				debugf("    Creating subscription %q", subscriptionID)
-				_, err = client.CreateSubscription(ctx, subscriptionID, pubsub.SubscriptionConfig{Topic: topic})
+				spec.AckDeadline = d
+			case "retention":
+				d, err := time.ParseDuration(value)
+				if err != nil {
+					return spec, fmt.Errorf("invalid retention value %q in %q: %s", value, raw, err)
+				}
+				spec.RetentionDuration = d
+			case "dlq":
+				dlqParts := strings.SplitN(value, ":", 2)
+				if len(dlqParts) != 2 {
+					return spec, fmt.Errorf("malformed dlq value %q in %q: expected topic:maxDeliveryAttempts", value, raw)
+				}
+				n, err := strconv.Atoi(dlqParts[1])
+				if err != nil {
+					return spec, fmt.Errorf("invalid dlq max delivery attempts %q in %q: %s", dlqParts[1], raw, err)
+				}
+				spec.DeadLetterTopic = dlqParts[0]
+				spec.MaxDeliveryAttempts = n
+			case "filter":
+				spec.Filter = value
+			case "ordering":
+				spec.EnableOrdering = true
+			case "exactly_once":
+				spec.EnableExactlyOnce = true
+			case "min_backoff":
+				d, err := time.ParseDuration(value)
+				if err != nil {
+					return spec, fmt.Errorf("invalid min_backoff value %q in %q: %s", value, raw, err)
+				}
+				spec.MinBackoff = d
+			case "max_backoff":
+				d, err := time.ParseDuration(value)
+				if err != nil {
+					return spec, fmt.Errorf("invalid max_backoff value %q in %q: %s", value, raw, err)
+				}
+				spec.MaxBackoff = d
+			default:
+				return spec, fmt.Errorf("unknown subscription option %q in %q", key, raw)
+			}
+		}
+	}
+
+	spec.ID = strings.TrimSpace(id)
+	return spec, nil
+}
+
+// splitRespectingDelimiters splits s on sep, ignoring any sep byte that falls
+// inside a "..." string or a {...}/[...] option block, so values like
+// filter="a,b" or a topic's {schema=x,seed=y} options aren't torn apart. A
+// backslash escapes the character that follows it, so a quote escaped as \"
+// doesn't end the string early.
+func splitRespectingDelimiters(s string, sep byte) []string {
+	var parts []string
+	depth := 0
+	inQuotes := false
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			inQuotes = !inQuotes
+		case '{', '[':
+			if !inQuotes {
+				depth++
+			}
+		case '}', ']':
+			if !inQuotes {
+				depth--
+			}
+		default:
+			if s[i] == sep && depth == 0 && !inQuotes {
+				parts = append(parts, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	parts = append(parts, s[start:])
+	return parts
+}
+
+// unescapeQuoted strips the surrounding quotes from a `"..."` value and
+// resolves backslash escapes within it (e.g. \" becomes ", \\ becomes \).
+// Values that aren't quoted are returned unchanged.
+func unescapeQuoted(s string) string {
+	if len(s) < 2 || s[0] != '"' || s[len(s)-1] != '"' {
+		return s
+	}
+
+	inner := s[1 : len(s)-1]
+	var b strings.Builder
+	for i := 0; i < len(inner); i++ {
+		if inner[i] == '\\' && i+1 < len(inner) {
+			i++
+		}
+		b.WriteByte(inner[i])
+	}
+	return b.String()
+}
+
+// subscriptionConfig builds the full pubsub.SubscriptionConfig for spec,
+// pointed at topic and, if endPointURL is set, configured for push delivery.
+func subscriptionConfig(topic *pubsub.Topic, projectID string, spec SubscriptionSpec, endPointURL string) pubsub.SubscriptionConfig {
+	config := pubsub.SubscriptionConfig{
+		Topic:                     topic,
+		AckDeadline:               spec.AckDeadline,
+		RetentionDuration:         spec.RetentionDuration,
+		EnableMessageOrdering:     spec.EnableOrdering,
+		EnableExactlyOnceDelivery: spec.EnableExactlyOnce,
+		Filter:                    spec.Filter,
+	}
+
+	if endPointURL != "" {
+		config.PushConfig = pubsub.PushConfig{Endpoint: endPointURL}
+		if spec.PushAuthServiceAccount != "" {
+			config.PushConfig.AuthenticationMethod = &pubsub.OIDCToken{ServiceAccountEmail: spec.PushAuthServiceAccount}
+		}
+	}
+
+	if spec.DeadLetterTopic != "" {
+		config.DeadLetterPolicy = &pubsub.DeadLetterPolicy{
+			DeadLetterTopic:     fmt.Sprintf("projects/%s/topics/%s", projectID, spec.DeadLetterTopic),
+			MaxDeliveryAttempts: spec.MaxDeliveryAttempts,
+		}
+	}
+
+	if spec.MinBackoff != 0 || spec.MaxBackoff != 0 {
+		config.RetryPolicy = &pubsub.RetryPolicy{MinimumBackoff: spec.MinBackoff, MaximumBackoff: spec.MaxBackoff}
+	}
+
+	return config
+}
+
+// deadLetterPolicyEqual reports whether a and b describe the same
+// dead-letter policy, treating two nil policies as equal.
+func deadLetterPolicyEqual(a, b *pubsub.DeadLetterPolicy) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.DeadLetterTopic == b.DeadLetterTopic && a.MaxDeliveryAttempts == b.MaxDeliveryAttempts
+}
+
+// retryPolicyEqual reports whether a and b describe the same retry policy,
+// treating two nil policies as equal.
+func retryPolicyEqual(a, b *pubsub.RetryPolicy) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.MinimumBackoff == b.MinimumBackoff && a.MaximumBackoff == b.MaximumBackoff
+}
+
+// createOrUpdateSubscription creates the subscription described by spec on
+// topic, or, in -reconcile mode, reuses the existing subscription and updates
+// whichever of its push endpoint, ack deadline, retention duration, dead-letter
+// policy, retry policy and exactly-once delivery drifted from spec. A spec
+// duration left at its zero value is treated as "leave unchanged" rather than
+// as drift, since the DSL has no syntax to distinguish "unset" from "zero".
+func createOrUpdateSubscription(ctx context.Context, client *pubsub.Client, topic *pubsub.Topic, projectID, topicID string, spec SubscriptionSpec, endPointURL string) error {
+	config := subscriptionConfig(topic, projectID, spec, endPointURL)
+
+	if *reconcile {
+		sub := client.Subscription(spec.ID)
+		exists, err := sub.Exists(ctx)
+		if err != nil {
+			return fmt.Errorf("Unable to check if subscription %q exists for project %q: %s", spec.ID, projectID, err)
+		}
+		if exists {
+			existing, err := sub.Config(ctx)
+			if err != nil {
+				return fmt.Errorf("Unable to get config for subscription %q for project %q: %s", spec.ID, projectID, err)
+			}
+
+			update := pubsub.SubscriptionConfigToUpdate{}
+			drifted := false
+
+			if existing.PushConfig.Endpoint != endPointURL {
+				update.PushConfig = &config.PushConfig
+				drifted = true
+			}
+			if spec.AckDeadline != 0 && existing.AckDeadline != spec.AckDeadline {
+				update.AckDeadline = spec.AckDeadline
+				drifted = true
+			}
+			if spec.RetentionDuration != 0 && existing.RetentionDuration != spec.RetentionDuration {
+				update.RetentionDuration = spec.RetentionDuration
+				drifted = true
+			}
+			if spec.DeadLetterTopic != "" && !deadLetterPolicyEqual(existing.DeadLetterPolicy, config.DeadLetterPolicy) {
+				update.DeadLetterPolicy = config.DeadLetterPolicy
+				drifted = true
+			}
+			if (spec.MinBackoff != 0 || spec.MaxBackoff != 0) && !retryPolicyEqual(existing.RetryPolicy, config.RetryPolicy) {
+				update.RetryPolicy = config.RetryPolicy
+				drifted = true
+			}
+			if existing.EnableExactlyOnceDelivery != spec.EnableExactlyOnce {
+				update.EnableExactlyOnceDelivery = spec.EnableExactlyOnce
+				drifted = true
+			}
+
+			if !drifted {
+				debugf("  Subscription %q already up to date", spec.ID)
+				return nil
+			}
+
+			debugf("    Updating subscription %q - endpoint %q", spec.ID, endPointURL)
+			if _, err := sub.Update(ctx, update); err != nil {
+				return fmt.Errorf("Unable to update subscription %q on topic %q for project %q using push endpoint %q: %s", spec.ID, topicID, projectID, endPointURL, err)
+			}
+			return nil
+		}
+	}
+
+	if endPointURL != "" {
+		debugf("    Creating subscription %q - endpoint %q", spec.ID, endPointURL)
+	} else {
+		debugf("    Creating subscription %q", spec.ID)
+	}
+	_, err := client.CreateSubscription(ctx, spec.ID, config)
+	if err != nil {
+		return fmt.Errorf("Unable to create subscription %q on topic %q for project %q: %s", spec.ID, topicID, projectID, err)
+	}
+	return nil
+}
+
+// pruneUndeclared deletes topics and subscriptions in projectID that are not
+// present in declaredTopics/declaredSubs. Callers must also mark any topic
+// referenced as a dead-letter target in declaredTopics, so a declared
+// subscription's DLQ isn't pruned out from under it. Only called when
+// -prune is set.
+func pruneUndeclared(ctx context.Context, client *pubsub.Client, projectID string, declaredTopics, declaredSubs map[string]bool) error {
+	existingTopics, err := listTopics(client)
+	if err != nil {
+		return fmt.Errorf("Unable to list topics for project %q: %s", projectID, err)
+	}
+
+	for _, topic := range existingTopics {
+		topicID := topic.ID()
+
+		subscriptions, err := listSubscriptions(client, topicID)
+		if err != nil {
+			return fmt.Errorf("Unable to list subscriptions for topic %q for project %q: %s", topicID, projectID, err)
+		}
+		for _, sub := range subscriptions {
+			if declaredSubs[sub.ID()] {
+				continue
+			}
+			debugf("  Deleting undeclared subscription %q", sub.ID())
+			if err := sub.Delete(ctx); err != nil {
+				return fmt.Errorf("Unable to delete subscription %q for project %q: %s", sub.ID(), projectID, err)
+			}
+		}
+
+		if declaredTopics[topicID] {
+			continue
+		}
+		debugf("  Deleting undeclared topic %q", topicID)
+		if err := topic.Delete(ctx); err != nil {
+			return fmt.Errorf("Unable to delete topic %q for project %q: %s", topicID, projectID, err)
+		}
+	}
+
+	return nil
+}
+
+// minLitePerPartitionBytes is the smallest per-partition storage reservation
+// the Pub/Sub Lite API accepts.
+const minLitePerPartitionBytes = 30 * 1024 * 1024 * 1024 // 30 GiB
+
+// LiteTopicSpec describes a Pub/Sub Lite topic's provisioning options parsed
+// from the topic1{partitions=2,capacity=4MiB,storage=30GiB,retention=24h} syntax.
+type LiteTopicSpec struct {
+	ID                string
+	PartitionCount    int
+	CapacityMiBPerSec int
+	PerPartitionBytes int64
+	RetentionDuration time.Duration
+}
+
+// parseLiteTopicSpec parses a "topicID" or
+// "topicID{partitions=N,capacity=NMiB,storage=NGiB,retention=24h}" token from
+// the PUBSUB_LITE_PROJECT{N} syntax.
+func parseLiteTopicSpec(raw string) (LiteTopicSpec, error) {
+	spec := LiteTopicSpec{PartitionCount: 1, CapacityMiBPerSec: 4, PerPartitionBytes: minLitePerPartitionBytes}
+
+	id := raw
+	if idx := strings.Index(raw, "{"); idx != -1 {
+		if !strings.HasSuffix(raw, "}") {
+			return spec, fmt.Errorf("malformed topic options %q: missing closing '}'", raw)
+		}
+		id = raw[:idx]
+
+		for _, option := range strings.Split(raw[idx+1:len(raw)-1], ",") {
+			kv := strings.SplitN(option, "=", 2)
+			if len(kv) != 2 {
+				return spec, fmt.Errorf("malformed topic option %q in %q", option, raw)
+			}
+			key, value := strings.TrimSpace(kv[0]), strings.TrimSpace(kv[1])
+
+			switch key {
+			case "partitions":
+				n, err := strconv.Atoi(value)
+				if err != nil {
+					return spec, fmt.Errorf("invalid partitions value %q in %q: %s", value, raw, err)
+				}
+				spec.PartitionCount = n
+			case "capacity":
+				miB, err := strconv.Atoi(strings.TrimSuffix(value, "MiB"))
+				if err != nil {
+					return spec, fmt.Errorf("invalid capacity value %q in %q: %s", value, raw, err)
+				}
+				spec.CapacityMiBPerSec = miB
+			case "storage":
+				giB, err := strconv.ParseInt(strings.TrimSuffix(value, "GiB"), 10, 64)
 				if err != nil {
-					return fmt.Errorf("Unable to create subscription %q on topic %q for project %q: %s", subscriptionID, topicID, projectID, err)
+					return spec, fmt.Errorf("invalid storage value %q in %q: %s", value, raw, err)
 				}
+				spec.PerPartitionBytes = giB * 1024 * 1024 * 1024
+			case "retention":
+				d, err := time.ParseDuration(value)
+				if err != nil {
+					return spec, fmt.Errorf("invalid retention value %q in %q: %s", value, raw, err)
+				}
+				spec.RetentionDuration = d
+			default:
+				return spec, fmt.Errorf("unknown topic option %q in %q", key, raw)
+			}
+		}
+	}
+
+	spec.ID = strings.TrimSpace(id)
+	return spec, nil
+}
+
+// createLite provisions Pub/Sub Lite topics and subscriptions in the given
+// zone using the Lite admin API.
+func createLite(ctx context.Context, projectID, zone string, topics Topics) error {
+	region, err := pubsublite.ZoneToRegion(zone)
+	if err != nil {
+		return fmt.Errorf("Unable to derive region from zone %q: %s", zone, err)
+	}
+
+	client, err := pubsublite.NewAdminClient(ctx, region)
+	if err != nil {
+		return fmt.Errorf("Unable to create Lite admin client for project %q in zone %q: %s", projectID, zone, err)
+	}
+	defer client.Close()
+
+	debugf("Lite admin client connected with project ID %q in zone %q", projectID, zone)
+
+	for topicSpecRaw, subscriptions := range topics {
+		spec, err := parseLiteTopicSpec(topicSpecRaw)
+		if err != nil {
+			return err
+		}
+
+		topicPath := pubsublite.TopicPath{Project: projectID, Zone: zone, TopicID: spec.ID}
+		debugf("  Creating Lite topic %q - %d partitions, %dMiB/s", spec.ID, spec.PartitionCount, spec.CapacityMiBPerSec)
+		_, err = client.CreateTopic(ctx, pubsublite.TopicConfig{
+			Name:                       topicPath,
+			PartitionCount:             spec.PartitionCount,
+			PublishCapacityMiBPerSec:   spec.CapacityMiBPerSec,
+			SubscribeCapacityMiBPerSec: spec.CapacityMiBPerSec,
+			PerPartitionBytes:          spec.PerPartitionBytes,
+			RetentionDuration:          spec.RetentionDuration,
+		})
+		if err != nil {
+			return fmt.Errorf("Unable to create Lite topic %q for project %q in zone %q: %s", spec.ID, projectID, zone, err)
+		}
+
+		for _, subscriptionID := range subscriptions {
+			subscriptionID = strings.TrimSpace(subscriptionID)
+			subscriptionPath := pubsublite.SubscriptionPath{Project: projectID, Zone: zone, SubscriptionID: subscriptionID}
+			debugf("    Creating Lite subscription %q", subscriptionID)
+			_, err = client.CreateSubscription(ctx, pubsublite.SubscriptionConfig{
+				Name:                subscriptionPath,
+				Topic:               topicPath,
+				DeliveryRequirement: pubsublite.DeliverImmediately,
+			})
+			if err != nil {
+				return fmt.Errorf("Unable to create Lite subscription %q on topic %q for project %q in zone %q: %s", subscriptionID, spec.ID, projectID, zone, err)
 			}
 		}
 	}
@@ -167,10 +841,47 @@ func create(ctx context.Context, projectID string, topics Topics) error {
 	return nil
 }
 
+// displayLiteResult prints a summary of the Lite topics and subscriptions
+// provisioned for projectID in zone, including each subscription's assigned
+// partition range.
+func displayLiteResult(projectID, zone string, topics Topics) {
+	ctx := context.Background()
+	region, err := pubsublite.ZoneToRegion(zone)
+	if err != nil {
+		return
+	}
+	client, err := pubsublite.NewAdminClient(ctx, region)
+	if err != nil {
+		return
+	}
+	defer client.Close()
+
+	for topicSpecRaw, subscriptions := range topics {
+		spec, err := parseLiteTopicSpec(topicSpecRaw)
+		if err != nil {
+			continue
+		}
+
+		debugf("Lite Topic: %s\n", spec.ID)
+		partitions, err := client.TopicPartitionCount(ctx, pubsublite.TopicPath{Project: projectID, Zone: zone, TopicID: spec.ID})
+		if err != nil {
+			fatalf("Failed to get partition count for Lite topic %q: %s", spec.ID, err)
+			return
+		}
+
+		for _, subscriptionID := range subscriptions {
+			subscriptionID = strings.TrimSpace(subscriptionID)
+			fmt.Printf("  Subscription: %s - Partitions: 0-%d\n", subscriptionID, partitions-1)
+		}
+	}
+}
+
 func main() {
 	flag.Parse()
 	flag.Usage = func() {
 		fmt.Printf(`Usage: env PUBSUB_PROJECT1="project1,topic1,topic2:subscription1,topic3:subscription2+enpoint1" %s`+"\n", os.Args[0])
+		fmt.Printf("A topic token may carry options in braces, e.g. topic1{schema=my-schema,encoding=JSON,seed=./fixtures/*.json}.\n")
+		fmt.Printf("A subscription token may carry options in brackets, e.g. sub1[ack=30s,dlq=deadtopic:5,filter=\"...\",ordering,exactly_once].\n")
 		flag.PrintDefaults()
 	}
 
@@ -184,23 +895,69 @@ func main() {
 		return
 	}
 
+	if *prune && !*reconcile {
+		fatalf("-prune requires -reconcile")
+	}
+
+	found := false
+
+	// -config is processed before the env-var DSL but doesn't replace it:
+	// both can be used in the same run.
+	if *config != "" {
+		cfg, err := loadConfig(*config)
+		if err != nil {
+			fatalf(err.Error())
+		}
+
+		for _, project := range cfg.Projects {
+			found = true
+
+			if err := createFromConfig(context.Background(), project); err != nil {
+				fatalf(err.Error())
+			}
+
+			displayResult(project.ID)
+		}
+	}
+
+	// Cycle over the numbered PUBSUB_SCHEMA environment variables. Schemas are
+	// provisioned ahead of PUBSUB_PROJECT{N} so topics can reference them.
+	for i := 1; ; i++ {
+		currentEnv := fmt.Sprintf("PUBSUB_SCHEMA%d", i)
+		env := os.Getenv(currentEnv)
+		if env == "" {
+			break
+		}
+		found = true
+
+		parts := strings.SplitN(env, ",", 4)
+		if len(parts) != 4 {
+			fatalf("%s: Expected projectID,schemaID,type,definitionFile", currentEnv)
+		}
+		projectID, schemaID, schemaType, definitionFile := parts[0], parts[1], parts[2], parts[3]
+
+		definition, err := os.ReadFile(definitionFile)
+		if err != nil {
+			fatalf("%s: Unable to read schema definition file %q: %s", currentEnv, definitionFile, err)
+		}
+
+		if err := createSchema(context.Background(), projectID, schemaID, schemaType, string(definition)); err != nil {
+			fatalf(err.Error())
+		}
+	}
+
 	// Cycle over the numbered PUBSUB_PROJECT environment variables.
 	for i := 1; ; i++ {
 		// Fetch the enviroment variable. If it doesn't exist, break out.
 		currentEnv := fmt.Sprintf("PUBSUB_PROJECT%d", i)
 		env := os.Getenv(currentEnv)
 		if env == "" {
-			// If this is the first environment variable, print the usage info.
-			if i == 1 {
-				flag.Usage()
-				os.Exit(1)
-			}
-
 			break
 		}
+		found = true
 
 		// Separate the projectID from the topic definitions.
-		parts := strings.Split(env, ",")
+		parts := splitRespectingDelimiters(env, ',')
 		if len(parts) < 2 {
 			fatalf("%s: Expected at least 1 topic to be defined", currentEnv)
 		}
@@ -208,7 +965,7 @@ func main() {
 		// Separate the topicID from the subscription IDs.
 		topics := make(Topics)
 		for _, part := range parts[1:] {
-			topicParts := strings.Split(part, ":")
+			topicParts := splitRespectingDelimiters(part, ':')
 			topics[topicParts[0]] = topicParts[1:]
 		}
 
@@ -219,4 +976,42 @@ func main() {
 
 		displayResult(parts[0])
 	}
+
+	// Cycle over the numbered PUBSUB_LITE_PROJECT environment variables.
+	for i := 1; ; i++ {
+		currentEnv := fmt.Sprintf("PUBSUB_LITE_PROJECT%d", i)
+		env := os.Getenv(currentEnv)
+		if env == "" {
+			break
+		}
+		found = true
+
+		// Separate the projectID and zone from the topic definitions.
+		parts := splitRespectingDelimiters(env, ',')
+		if len(parts) < 3 {
+			fatalf("%s: Expected a project ID, a zone and at least 1 topic to be defined", currentEnv)
+		}
+		projectID, zone := parts[0], parts[1]
+
+		// Separate the topic spec from the subscription IDs.
+		topics := make(Topics)
+		for _, part := range parts[2:] {
+			topicParts := splitRespectingDelimiters(part, ':')
+			topics[topicParts[0]] = topicParts[1:]
+		}
+
+		// Create the Lite project and all its topics and subscriptions.
+		if err := createLite(context.Background(), projectID, zone, topics); err != nil {
+			fatalf(err.Error())
+		}
+
+		displayLiteResult(projectID, zone, topics)
+	}
+
+	// If -config declared no projects and none of PUBSUB_SCHEMA{N},
+	// PUBSUB_PROJECT{N} or PUBSUB_LITE_PROJECT{N} were set, print usage info.
+	if !found {
+		flag.Usage()
+		os.Exit(1)
+	}
 }
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSplitRespectingDelimiters(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		sep  byte
+		want []string
+	}{
+		{"plain", "a,b,c", ',', []string{"a", "b", "c"}},
+		{"brace block", "a{schema=x,seed=y},b", ',', []string{"a{schema=x,seed=y}", "b"}},
+		{"bracket block", "sub[ack=30s,dlq=dead:5]+host", ':', []string{"sub[ack=30s,dlq=dead:5]+host"}},
+		{"quoted value", `sub[filter="a,b"],other`, ',', []string{`sub[filter="a,b"]`, "other"}},
+		{"escaped quote inside string", `sub[filter="a\",b"],other`, ',', []string{`sub[filter="a\",b"]`, "other"}},
+		{"no separator", "a", ',', []string{"a"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := splitRespectingDelimiters(tt.in, tt.sep)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitRespectingDelimiters(%q, %q) = %v, want %v", tt.in, tt.sep, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnescapeQuoted(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"unquoted", "plain", "plain"},
+		{"simple quoted", `"a,b"`, "a,b"},
+		{"escaped quote", `"a\"b"`, `a"b`},
+		{"escaped backslash", `"a\\b"`, `a\b`},
+		{"too short to be quoted", `"`, `"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := unescapeQuoted(tt.in); got != tt.want {
+				t.Errorf("unescapeQuoted(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTopicSpec(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		want    TopicSpec
+		wantErr bool
+	}{
+		{"bare id", "topic1", TopicSpec{ID: "topic1"}, false},
+		{"with schema and encoding", "topic1{schema=my-schema,encoding=BINARY}", TopicSpec{ID: "topic1", Schema: "my-schema", Encoding: "BINARY"}, false},
+		{"with seed", "topic1{seed=./fixtures/*.json}", TopicSpec{ID: "topic1", Seed: "./fixtures/*.json"}, false},
+		{"missing closing brace", "topic1{schema=x", TopicSpec{}, true},
+		{"unknown option", "topic1{bogus=x}", TopicSpec{}, true},
+		{"malformed option", "topic1{schema}", TopicSpec{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseTopicSpec(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseTopicSpec(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("parseTopicSpec(%q) = %+v, want %+v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSubscriptionSpec(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		want    SubscriptionSpec
+		wantErr bool
+	}{
+		{"bare id", "sub1", SubscriptionSpec{ID: "sub1"}, false},
+		{
+			"full options",
+			`sub1[ack=30s,retention=1h,dlq=deadtopic:5,filter="attributes.foo=\"bar\"",ordering,exactly_once,min_backoff=10s,max_backoff=60s]`,
+			SubscriptionSpec{
+				ID:                  "sub1",
+				AckDeadline:         30 * time.Second,
+				RetentionDuration:   time.Hour,
+				DeadLetterTopic:     "deadtopic",
+				MaxDeliveryAttempts: 5,
+				EnableOrdering:      true,
+				EnableExactlyOnce:   true,
+				Filter:              `attributes.foo="bar"`,
+				MinBackoff:          10 * time.Second,
+				MaxBackoff:          60 * time.Second,
+			},
+			false,
+		},
+		{"missing closing bracket", "sub1[ack=30s", SubscriptionSpec{}, true},
+		{"unknown option", "sub1[bogus=1]", SubscriptionSpec{}, true},
+		{"malformed dlq", "sub1[dlq=deadtopic]", SubscriptionSpec{}, true},
+		{"invalid ack duration", "sub1[ack=notaduration]", SubscriptionSpec{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseSubscriptionSpec(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseSubscriptionSpec(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseSubscriptionSpec(%q) = %+v, want %+v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseLiteTopicSpec(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		want    LiteTopicSpec
+		wantErr bool
+	}{
+		{"bare id uses defaults", "topic1", LiteTopicSpec{ID: "topic1", PartitionCount: 1, CapacityMiBPerSec: 4, PerPartitionBytes: minLitePerPartitionBytes}, false},
+		{
+			"full options",
+			"topic1{partitions=3,capacity=8MiB,storage=60GiB,retention=24h}",
+			LiteTopicSpec{ID: "topic1", PartitionCount: 3, CapacityMiBPerSec: 8, PerPartitionBytes: 60 * 1024 * 1024 * 1024, RetentionDuration: 24 * time.Hour},
+			false,
+		},
+		{"missing closing brace", "topic1{partitions=3", LiteTopicSpec{}, true},
+		{"unknown option", "topic1{bogus=1}", LiteTopicSpec{}, true},
+		{"invalid partitions", "topic1{partitions=x}", LiteTopicSpec{}, true},
+		{"invalid storage", "topic1{storage=x}", LiteTopicSpec{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseLiteTopicSpec(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseLiteTopicSpec(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("parseLiteTopicSpec(%q) = %+v, want %+v", tt.in, got, tt.want)
+			}
+		})
+	}
+}